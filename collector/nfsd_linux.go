@@ -14,7 +14,9 @@
 package collector
 
 import (
+	"errors"
 	"fmt"
+	"os"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/procfs"
@@ -25,6 +27,21 @@ import (
 // See: https://www.svennd.be/nfsd-stats-explained-procnetrpcnfsd/
 type nfsdCollector struct {
 	fs procfs.FS
+
+	replyCacheHitsDesc     *prometheus.Desc
+	replyCacheMissesDesc   *prometheus.Desc
+	replyCacheNoCacheDesc  *prometheus.Desc
+	fileHandlesStaleDesc   *prometheus.Desc
+	diskBytesReadDesc      *prometheus.Desc
+	diskBytesWrittenDesc   *prometheus.Desc
+	serverThreadsDesc      *prometheus.Desc
+	readAheadCacheSizeDesc *prometheus.Desc
+	readAheadNotFoundDesc  *prometheus.Desc
+	packetsDesc            *prometheus.Desc
+	connectionsDesc        *prometheus.Desc
+	requestsDesc           *prometheus.Desc
+	rpcOperationsDesc      *prometheus.Desc
+	rpcErrorsDesc          *prometheus.Desc
 }
 
 func init() {
@@ -35,23 +52,165 @@ const (
 	nfsdSubsystem = "nfsd"
 )
 
+// nfsdVersion2Procedures is the fixed, ordered list of NFSv2 procedures as
+// reported in the "proc2" line of /proc/net/rpc/nfsd. The order matches
+// nfsd_procedures2 in fs/nfsd/stats.c.
+var nfsdVersion2Procedures = []string{
+	"null", "getattr", "setattr", "root", "lookup", "readlink", "read",
+	"wrcache", "write", "create", "remove", "rename", "link", "symlink",
+	"mkdir", "rmdir", "readdir", "fsstat",
+}
+
+// nfsdVersion3Procedures is the fixed, ordered list of NFSv3 procedures as
+// reported in the "proc3" line of /proc/net/rpc/nfsd. The order matches
+// nfsd_procedures3 in fs/nfsd/stats.c.
+var nfsdVersion3Procedures = []string{
+	"null", "getattr", "setattr", "lookup", "access", "readlink", "read",
+	"write", "create", "mkdir", "symlink", "mknod", "remove", "rmdir",
+	"rename", "link", "readdir", "readdirplus", "fsstat", "fsinfo",
+	"pathconf", "commit",
+}
+
+// nfsdVersion4Procedures is the fixed, ordered list of NFSv4 compound
+// procedures as reported in the "proc4" line of /proc/net/rpc/nfsd.
+var nfsdVersion4Procedures = []string{
+	"null", "compound",
+}
+
+// nfsdVersion4OperationNames labels the fields of nfs.V4Ops, in the same
+// order they're declared (Op0Unused..RelLockOwner), as reported in the
+// "proc4ops" line of /proc/net/rpc/nfsd.
+var nfsdVersion4OperationNames = []string{
+	"op0-unused", "op1-unused", "op2-future",
+	"access", "close", "commit", "create", "delegpurge", "delegreturn",
+	"getattr", "getfh", "link", "lock", "lockt", "locku", "lookup",
+	"lookup_root", "nverify", "open", "openattr", "open_confirm",
+	"open_downgrade", "putfh", "putpubfh", "putrootfh", "read", "readdir",
+	"readlink", "remove", "rename", "renew", "restorefh", "savefh",
+	"secinfo", "setattr", "setclientid", "setclientid_confirm", "verify",
+	"write", "rel_lockowner",
+}
+
 // NewNFSdCollector returns a new Collector exposing /proc/net/rpc/nfsd statistics.
 func NewNFSdCollector() (Collector, error) {
 	fs, err := procfs.NewFS(*procPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open procfs: %v", err)
+		return nil, fmt.Errorf("failed to open procfs: %w", err)
 	}
 
+	return newNFSdCollector(fs), nil
+}
+
+// newNFSdCollector builds a nfsdCollector around an already-opened procfs.FS,
+// pre-building all of its prometheus.Desc values once rather than on every
+// scrape. Split out from NewNFSdCollector so benchmarks/tests can construct a
+// collector without touching the real /proc/net/rpc/nfsd file.
+func newNFSdCollector(fs procfs.FS) *nfsdCollector {
 	return &nfsdCollector{
 		fs: fs,
-	}, nil
+
+		replyCacheHitsDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, nfsdSubsystem, "reply_cache_hits_total"),
+			"NFSd Reply Cache client did not receive a reply and decided to re-transmit its request and the reply was cached. (bad).",
+			nil, nil,
+		),
+		replyCacheMissesDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, nfsdSubsystem, "reply_cache_misses_total"),
+			"NFSd Reply Cache an operation that requires caching (idempotent).",
+			nil, nil,
+		),
+		replyCacheNoCacheDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, nfsdSubsystem, "reply_cache_nocache_total"),
+			"NFSd Reply Cache non-idempotent operations (rename/delete/…).",
+			nil, nil,
+		),
+		fileHandlesStaleDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, nfsdSubsystem, "file_handles_stale_total"),
+			"NFSd stale file handles",
+			nil, nil,
+		),
+		diskBytesReadDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, nfsdSubsystem, "disk_bytes_read_total"),
+			"NFSd bytes read",
+			nil, nil,
+		),
+		diskBytesWrittenDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, nfsdSubsystem, "disk_bytes_written_total"),
+			"NFSd bytes written",
+			nil, nil,
+		),
+		serverThreadsDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, nfsdSubsystem, "server_threads"),
+			"NFSd how many kernel threads are running",
+			nil, nil,
+		),
+		readAheadCacheSizeDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, nfsdSubsystem, "read_ahead_cache_size_blocks"),
+			"NFSd how large the read ahead cache in blocks",
+			nil, nil,
+		),
+		readAheadNotFoundDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, nfsdSubsystem, "read_ahead_cache_not_found_total"),
+			"NFSd how large the read ahead cache in blocks",
+			nil, nil,
+		),
+		packetsDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, nfsdSubsystem, "packets_total"),
+			"NFSd how many network packets have been sent/recieved",
+			[]string{"proto"}, nil,
+		),
+		connectionsDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, nfsdSubsystem, "connections_total"),
+			"NFSd how many TCP connections have been made",
+			nil, nil,
+		),
+		requestsDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, nfsdSubsystem, "requests_total"),
+			"NFSd requests by protocol version and procedure/operation.",
+			[]string{"proto", "method"}, nil,
+		),
+		rpcOperationsDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, nfsdSubsystem, "rpc_operations_total"),
+			"NFSd total number of RPC calls received.",
+			nil, nil,
+		),
+		rpcErrorsDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, nfsdSubsystem, "rpc_errors_total"),
+			"NFSd total number of RPC errors, by reason.",
+			[]string{"reason"}, nil,
+		),
+	}
+}
+
+// Describe implements Collector.
+func (c *nfsdCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.replyCacheHitsDesc
+	ch <- c.replyCacheMissesDesc
+	ch <- c.replyCacheNoCacheDesc
+	ch <- c.fileHandlesStaleDesc
+	ch <- c.diskBytesReadDesc
+	ch <- c.diskBytesWrittenDesc
+	ch <- c.serverThreadsDesc
+	ch <- c.readAheadCacheSizeDesc
+	ch <- c.readAheadNotFoundDesc
+	ch <- c.packetsDesc
+	ch <- c.connectionsDesc
+	ch <- c.requestsDesc
+	ch <- c.rpcOperationsDesc
+	ch <- c.rpcErrorsDesc
 }
 
 // Update implements Collector.
 func (c *nfsdCollector) Update(ch chan<- prometheus.Metric) error {
 	stats, err := c.fs.NFSdServerRPCStats()
 	if err != nil {
-		return fmt.Errorf("failed to retrieve nfsd stats: %v", err)
+		if errors.Is(err, os.ErrNotExist) {
+			// NFSd is not running, or the kernel module is not loaded: treat
+			// this as the collector having no data rather than a hard error,
+			// so the scrape isn't logged as failing every interval.
+			return ErrNoData
+		}
+		return fmt.Errorf("failed to retrieve nfsd stats: %w", err)
 	}
 
 	c.updateNFSdReplyCacheStats(ch, &stats.ReplyCache)
@@ -60,135 +219,119 @@ func (c *nfsdCollector) Update(ch chan<- prometheus.Metric) error {
 	c.updateNFSdThreadsStats(ch, &stats.Threads)
 	c.updateNFSdReadAheadCacheStats(ch, &stats.ReadAheadCache)
 	c.updateNFSdNetworkStats(ch, &stats.Network)
+	c.updateNFSdRequestsStats(ch, stats)
+	c.updateNFSdServerRPCStats(ch, &stats.ServerRPC)
 
 	return nil
 }
 
 // updateNFSdReplyCacheStats collects statistics for the reply cache.
 func (c *nfsdCollector) updateNFSdReplyCacheStats(ch chan<- prometheus.Metric, s *nfs.ReplyCache) {
-	ch <- prometheus.MustNewConstMetric(
-		prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, nfsdSubsystem, "reply_cache_hits_total"),
-			"NFSd Reply Cache client did not receive a reply and decided to re-transmit its request and the reply was cached. (bad).",
-			nil,
-			nil,
-		),
-		prometheus.CounterValue,
-		float64(s.Hits))
-	ch <- prometheus.MustNewConstMetric(
-		prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, nfsdSubsystem, "reply_cache_misses_total"),
-			"NFSd Reply Cache an operation that requires caching (idempotent).",
-			nil,
-			nil,
-		),
-		prometheus.CounterValue,
-		float64(s.Misses))
-	ch <- prometheus.MustNewConstMetric(
-		prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, nfsdSubsystem, "reply_cache_nocache_total"),
-			"NFSd Reply Cache non-idempotent operations (rename/delete/…).",
-			nil,
-			nil,
-		),
-		prometheus.CounterValue,
-		float64(s.NoCache))
+	ch <- prometheus.MustNewConstMetric(c.replyCacheHitsDesc, prometheus.CounterValue, float64(s.Hits))
+	ch <- prometheus.MustNewConstMetric(c.replyCacheMissesDesc, prometheus.CounterValue, float64(s.Misses))
+	ch <- prometheus.MustNewConstMetric(c.replyCacheNoCacheDesc, prometheus.CounterValue, float64(s.NoCache))
 }
 
 // updateNFSdFileHandlesStats collects statistics for the file handles.
 func (c *nfsdCollector) updateNFSdFileHandlesStats(ch chan<- prometheus.Metric, s *nfs.FileHandles) {
-	ch <- prometheus.MustNewConstMetric(
-		prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, nfsdSubsystem, "file_handles_stale_total"),
-			"NFSd stale file handles",
-			nil,
-			nil,
-		),
-		prometheus.CounterValue,
-		float64(s.Stale))
+	ch <- prometheus.MustNewConstMetric(c.fileHandlesStaleDesc, prometheus.CounterValue, float64(s.Stale))
 	// NOTE: Other FileHandles entries are unused in the kernel.
 }
 
 // updateNFSdInputOutputStats collects statistics for the bytes in/out.
 func (c *nfsdCollector) updateNFSdInputOutputStats(ch chan<- prometheus.Metric, s *nfs.InputOutput) {
-	ch <- prometheus.MustNewConstMetric(
-		prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, nfsdSubsystem, "disk_bytes_read_total"),
-			"NFSd bytes read",
-			nil,
-			nil,
-		),
-		prometheus.CounterValue,
-		float64(s.Read))
-	ch <- prometheus.MustNewConstMetric(
-		prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, nfsdSubsystem, "disk_bytes_written_total"),
-			"NFSd bytes written",
-			nil,
-			nil,
-		),
-		prometheus.CounterValue,
-		float64(s.Write))
+	ch <- prometheus.MustNewConstMetric(c.diskBytesReadDesc, prometheus.CounterValue, float64(s.Read))
+	ch <- prometheus.MustNewConstMetric(c.diskBytesWrittenDesc, prometheus.CounterValue, float64(s.Write))
 }
 
 // updateNFSdThreadsStats collects statistics for kernel server threads.
 func (c *nfsdCollector) updateNFSdThreadsStats(ch chan<- prometheus.Metric, s *nfs.Threads) {
-	ch <- prometheus.MustNewConstMetric(
-		prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, nfsdSubsystem, "server_threads"),
-			"NFSd how many kernel threads are running",
-			nil,
-			nil,
-		),
-		prometheus.GaugeValue,
-		float64(s.Threads))
+	ch <- prometheus.MustNewConstMetric(c.serverThreadsDesc, prometheus.GaugeValue, float64(s.Threads))
 }
 
 // updateNFSdReadAheadCacheStats collects statistics for the read ahead cache.
 func (c *nfsdCollector) updateNFSdReadAheadCacheStats(ch chan<- prometheus.Metric, s *nfs.ReadAheadCache) {
-	ch <- prometheus.MustNewConstMetric(
-		prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, nfsdSubsystem, "read_ahead_cache_size_blocks"),
-			"NFSd how large the read ahead cache in blocks",
-			nil,
-			nil,
-		),
-		prometheus.GaugeValue,
-		float64(s.CacheSize))
-	ch <- prometheus.MustNewConstMetric(
-		prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, nfsdSubsystem, "read_ahead_cache_not_found_total"),
-			"NFSd how large the read ahead cache in blocks",
-			nil,
-			nil,
-		),
-		prometheus.CounterValue,
-		float64(s.NotFound))
+	ch <- prometheus.MustNewConstMetric(c.readAheadCacheSizeDesc, prometheus.GaugeValue, float64(s.CacheSize))
+	ch <- prometheus.MustNewConstMetric(c.readAheadNotFoundDesc, prometheus.CounterValue, float64(s.NotFound))
 }
 
 // updateNFSdNetworkStats collects statistics for network packets/connections.
 func (c *nfsdCollector) updateNFSdNetworkStats(ch chan<- prometheus.Metric, s *nfs.Network) {
-	packetDesc := prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, nfsdSubsystem, "packets_total"),
-		"NFSd how many network packets have been sent/recieved",
-		[]string{"proto"},
-		nil,
-	)
-	ch <- prometheus.MustNewConstMetric(
-		packetDesc,
-		prometheus.CounterValue,
-		float64(s.UDPCount), "udp")
-	ch <- prometheus.MustNewConstMetric(
-		packetDesc,
-		prometheus.CounterValue,
-		float64(s.TCPCount), "tcp")
-	ch <- prometheus.MustNewConstMetric(
-		prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, nfsdSubsystem, "connections_total"),
-			"NFSd how many TCP connections have been made",
-			nil,
-			nil,
-		),
-		prometheus.CounterValue,
-		float64(s.TCPConnect))
+	ch <- prometheus.MustNewConstMetric(c.packetsDesc, prometheus.CounterValue, float64(s.UDPCount), "udp")
+	ch <- prometheus.MustNewConstMetric(c.packetsDesc, prometheus.CounterValue, float64(s.TCPCount), "tcp")
+	ch <- prometheus.MustNewConstMetric(c.connectionsDesc, prometheus.CounterValue, float64(s.TCPConnect))
+}
+
+// updateNFSdRequestsStats collects the per-procedure/per-operation call
+// counts for NFSv2, NFSv3, NFSv4 compounds and NFSv4 operations. Procedure
+// and operation names are taken from fixed, version-specific tables matching
+// the declaration order of the corresponding procfs/nfs struct fields, so
+// the resulting "method" label cardinality is bounded by what nfs.V4Ops
+// actually exposes rather than by the full kernel op table.
+func (c *nfsdCollector) updateNFSdRequestsStats(ch chan<- prometheus.Metric, stats *nfs.ServerRPCStats) {
+	emit := func(proto string, names []string, values []uint64) {
+		for i, name := range names {
+			if i >= len(values) {
+				break
+			}
+			ch <- prometheus.MustNewConstMetric(
+				c.requestsDesc, prometheus.CounterValue, float64(values[i]), proto, name)
+		}
+	}
+
+	emit("2", nfsdVersion2Procedures, []uint64{
+		stats.V2Stats.Null, stats.V2Stats.GetAttr, stats.V2Stats.SetAttr,
+		stats.V2Stats.Root, stats.V2Stats.Lookup, stats.V2Stats.ReadLink,
+		stats.V2Stats.Read, stats.V2Stats.WrCache, stats.V2Stats.Write,
+		stats.V2Stats.Create, stats.V2Stats.Remove, stats.V2Stats.Rename,
+		stats.V2Stats.Link, stats.V2Stats.SymLink, stats.V2Stats.MkDir,
+		stats.V2Stats.RmDir, stats.V2Stats.ReadDir, stats.V2Stats.FsStat,
+	})
+
+	emit("3", nfsdVersion3Procedures, []uint64{
+		stats.V3Stats.Null, stats.V3Stats.GetAttr, stats.V3Stats.SetAttr,
+		stats.V3Stats.Lookup, stats.V3Stats.Access, stats.V3Stats.ReadLink,
+		stats.V3Stats.Read, stats.V3Stats.Write, stats.V3Stats.Create,
+		stats.V3Stats.MkDir, stats.V3Stats.SymLink, stats.V3Stats.MkNod,
+		stats.V3Stats.Remove, stats.V3Stats.RmDir, stats.V3Stats.Rename,
+		stats.V3Stats.Link, stats.V3Stats.ReadDir, stats.V3Stats.ReadDirPlus,
+		stats.V3Stats.FsStat, stats.V3Stats.FsInfo, stats.V3Stats.PathConf,
+		stats.V3Stats.Commit,
+	})
+
+	emit("4", nfsdVersion4Procedures,
+		[]uint64{stats.ServerV4Stats.Null, stats.ServerV4Stats.Compound})
+
+	ops := stats.V4Ops
+	emit("4", nfsdVersion4OperationNames, []uint64{
+		ops.Op0Unused, ops.Op1Unused, ops.Op2Future, ops.Access, ops.Close,
+		ops.Commit, ops.Create, ops.DelegPurge, ops.DelegReturn, ops.GetAttr,
+		ops.GetFH, ops.Link, ops.Lock, ops.Lockt, ops.Locku, ops.Lookup,
+		ops.LookupRoot, ops.Nverify, ops.Open, ops.OpenAttr, ops.OpenConfirm,
+		ops.OpenDowngrade, ops.PutFH, ops.PutPubFH, ops.PutRootFH, ops.Read,
+		ops.ReadDir, ops.ReadLink, ops.Remove, ops.Rename, ops.Renew,
+		ops.RestoreFH, ops.SaveFH, ops.SecInfo, ops.SetAttr, ops.SetClientID,
+		ops.SetClientIDConfirm, ops.Verify, ops.Write, ops.RelLockOwner,
+	})
+}
+
+// updateNFSdServerRPCStats collects statistics for the RPC layer, i.e. the
+// "rpc" line of /proc/net/rpc/nfsd: BadCnt is the total bad-call count,
+// which we split into its BadAuth, BadcInt (bad client) and BadFmt (XDR
+// decode errors) components so auth/protocol problems can be alerted on
+// directly; any remainder is reported under reason="other".
+func (c *nfsdCollector) updateNFSdServerRPCStats(ch chan<- prometheus.Metric, s *nfs.ServerRPC) {
+	ch <- prometheus.MustNewConstMetric(c.rpcOperationsDesc, prometheus.CounterValue, float64(s.RPCCount))
+
+	// BadAuth/BadcInt/BadFmt/BadCnt are independent kernel counters, not a
+	// locked snapshot, so a scrape can transiently observe their sum exceed
+	// BadCnt. Clamp instead of subtracting to avoid a uint64 underflow.
+	var other uint64
+	if known := s.BadAuth + s.BadcInt + s.BadFmt; s.BadCnt > known {
+		other = s.BadCnt - known
+	}
+	ch <- prometheus.MustNewConstMetric(c.rpcErrorsDesc, prometheus.CounterValue, float64(s.BadAuth), "badauth")
+	ch <- prometheus.MustNewConstMetric(c.rpcErrorsDesc, prometheus.CounterValue, float64(s.BadcInt), "badclnt")
+	ch <- prometheus.MustNewConstMetric(c.rpcErrorsDesc, prometheus.CounterValue, float64(s.BadFmt), "xdrcall")
+	ch <- prometheus.MustNewConstMetric(c.rpcErrorsDesc, prometheus.CounterValue, float64(other), "other")
 }
@@ -0,0 +1,174 @@
+// Copyright 2018 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/procfs"
+	"github.com/prometheus/procfs/nfs"
+)
+
+// collectMetrics runs fn against an unbuffered channel drained by a goroutine
+// and decodes every emitted prometheus.Metric into its protobuf form so tests can assert on
+// label values without standing up a full registry.
+func collectMetrics(t *testing.T, fn func(ch chan<- prometheus.Metric)) []*dto.Metric {
+	t.Helper()
+
+	ch := make(chan prometheus.Metric)
+	done := make(chan struct{})
+	var out []*dto.Metric
+	go func() {
+		defer close(done)
+		for m := range ch {
+			var pb dto.Metric
+			if err := m.Write(&pb); err != nil {
+				t.Errorf("writing metric: %v", err)
+				continue
+			}
+			out = append(out, &pb)
+		}
+	}()
+
+	fn(ch)
+	close(ch)
+	<-done
+
+	return out
+}
+
+func metricLabel(m *dto.Metric, name string) string {
+	for _, lp := range m.GetLabel() {
+		if lp.GetName() == name {
+			return lp.GetValue()
+		}
+	}
+	return ""
+}
+
+func TestUpdateNFSdRequestsStats(t *testing.T) {
+	stats := &nfs.ServerRPCStats{
+		V2Stats:       nfs.V2Stats{Null: 1, GetAttr: 2, FsStat: 3},
+		V3Stats:       nfs.V3Stats{Null: 4, Lookup: 5, Commit: 6},
+		ServerV4Stats: nfs.ServerV4Stats{Null: 7, Compound: 8},
+		V4Ops:         nfs.V4Ops{Access: 9, Read: 10},
+	}
+
+	c := newNFSdCollector(procfs.FS{})
+	got := collectMetrics(t, func(ch chan<- prometheus.Metric) {
+		c.updateNFSdRequestsStats(ch, stats)
+	})
+
+	wantCount := len(nfsdVersion2Procedures) + len(nfsdVersion3Procedures) +
+		len(nfsdVersion4Procedures) + len(nfsdVersion4OperationNames)
+	if len(got) != wantCount {
+		t.Fatalf("got %d requests_total samples, want %d", len(got), wantCount)
+	}
+
+	type sample struct {
+		proto, method string
+		value         float64
+	}
+	want := []sample{
+		{"2", "null", 1},
+		{"2", "getattr", 2},
+		{"2", "fsstat", 3},
+		{"3", "null", 4},
+		{"3", "lookup", 5},
+		{"3", "commit", 6},
+		{"4", "null", 7},
+		{"4", "compound", 8},
+		{"4", "access", 9},
+		{"4", "read", 10},
+	}
+	for _, w := range want {
+		found := false
+		for _, m := range got {
+			if metricLabel(m, "proto") == w.proto && metricLabel(m, "method") == w.method {
+				found = true
+				if v := m.GetCounter().GetValue(); v != w.value {
+					t.Errorf("proto=%s method=%s: got value %v, want %v", w.proto, w.method, v, w.value)
+				}
+				break
+			}
+		}
+		if !found {
+			t.Errorf("missing sample for proto=%s method=%s", w.proto, w.method)
+		}
+	}
+}
+
+func TestUpdateNFSdServerRPCStats(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		s         nfs.ServerRPC
+		wantOther float64
+	}{
+		{
+			name:      "accounted for",
+			s:         nfs.ServerRPC{RPCCount: 1000, BadCnt: 6, BadAuth: 2, BadcInt: 3, BadFmt: 1},
+			wantOther: 0,
+		},
+		{
+			name:      "unaccounted remainder",
+			s:         nfs.ServerRPC{RPCCount: 1000, BadCnt: 10, BadAuth: 2, BadcInt: 3, BadFmt: 1},
+			wantOther: 4,
+		},
+		{
+			// Independent kernel counters can be read mid-update, so BadCnt
+			// may transiently be less than BadAuth+BadcInt+BadFmt. "other"
+			// must clamp to zero rather than underflow.
+			name:      "transient underflow clamps to zero",
+			s:         nfs.ServerRPC{RPCCount: 1000, BadCnt: 1, BadAuth: 2, BadcInt: 3, BadFmt: 1},
+			wantOther: 0,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newNFSdCollector(procfs.FS{})
+			got := collectMetrics(t, func(ch chan<- prometheus.Metric) {
+				c.updateNFSdServerRPCStats(ch, &tc.s)
+			})
+
+			var other float64
+			found := false
+			for _, m := range got {
+				if metricLabel(m, "reason") == "other" {
+					other = m.GetCounter().GetValue()
+					found = true
+				}
+			}
+			if !found {
+				t.Fatal("missing rpc_errors_total{reason=\"other\"} sample")
+			}
+			if other != tc.wantOther {
+				t.Errorf("reason=other: got %v, want %v", other, tc.wantOther)
+			}
+		})
+	}
+}
+
+func TestNFSdCollectorUpdateNoData(t *testing.T) {
+	fs, err := procfs.NewFS(t.TempDir())
+	if err != nil {
+		t.Fatalf("procfs.NewFS: %v", err)
+	}
+	c := newNFSdCollector(fs)
+
+	err = c.Update(make(chan prometheus.Metric, 128))
+	if err != ErrNoData {
+		t.Fatalf("Update() error = %v, want ErrNoData", err)
+	}
+}
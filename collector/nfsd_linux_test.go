@@ -0,0 +1,69 @@
+// Copyright 2018 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/procfs"
+	"github.com/prometheus/procfs/nfs"
+)
+
+// benchmarkNFSdServerRPCStats returns a stats struct for the benchmark, which
+// runs every updateNFSd* helper, including the per-procedure and
+// per-operation metrics added alongside the descriptor precomputation. The
+// per-procedure and per-operation counters (V2Stats, V3Stats, ServerV4Stats,
+// V4Ops) are left at zero; the other sections hold non-zero values.
+func benchmarkNFSdServerRPCStats() *nfs.ServerRPCStats {
+	return &nfs.ServerRPCStats{
+		ReplyCache:     nfs.ReplyCache{Hits: 1, Misses: 2, NoCache: 3},
+		FileHandles:    nfs.FileHandles{Stale: 1},
+		InputOutput:    nfs.InputOutput{Read: 100, Write: 200},
+		Threads:        nfs.Threads{Threads: 8},
+		ReadAheadCache: nfs.ReadAheadCache{CacheSize: 32, NotFound: 4},
+		Network:        nfs.Network{UDPCount: 10, TCPCount: 20, TCPConnect: 5},
+		ServerRPC:      nfs.ServerRPC{RPCCount: 1000, BadCnt: 6, BadAuth: 2, BadcInt: 3, BadFmt: 1},
+	}
+}
+
+// BenchmarkNFSdCollectorUpdate measures the current scrape path, where every
+// prometheus.Desc is built once in newNFSdCollector rather than re-created
+// per call. b.ReportAllocs() is what demonstrates the precomputation win;
+// diff it against the parent commit (before the Desc fields were added)
+// with benchstat if a before/after comparison is needed.
+func BenchmarkNFSdCollectorUpdate(b *testing.B) {
+	c := newNFSdCollector(procfs.FS{})
+	stats := benchmarkNFSdServerRPCStats()
+	ch := make(chan prometheus.Metric, 128)
+
+	go func() {
+		for range ch {
+		}
+	}()
+	defer close(ch)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.updateNFSdReplyCacheStats(ch, &stats.ReplyCache)
+		c.updateNFSdFileHandlesStats(ch, &stats.FileHandles)
+		c.updateNFSdInputOutputStats(ch, &stats.InputOutput)
+		c.updateNFSdThreadsStats(ch, &stats.Threads)
+		c.updateNFSdReadAheadCacheStats(ch, &stats.ReadAheadCache)
+		c.updateNFSdNetworkStats(ch, &stats.Network)
+		c.updateNFSdRequestsStats(ch, stats)
+		c.updateNFSdServerRPCStats(ch, &stats.ServerRPC)
+	}
+}